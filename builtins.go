@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// ScriptError is the panic value raised by builtins that fail at runtime
+type ScriptError struct {
+	Message string
+}
+
+// Error for ScriptError
+func (e *ScriptError) Error() string {
+	return e.Message
+}
+
+// fail panics with a ScriptError carrying the formatted message
+func fail(format string, args ...interface{}) {
+	panic(&ScriptError{Message: fmt.Sprintf(format, args...)})
+}
+
+// Builtin functions callable from scripts, keyed by their qualified name
+var builtins = map[string]func(args []string) string{
+	"path.join": func(args []string) string {
+		return filepath.Join(args...)
+	},
+	"path.base": func(args []string) string {
+		expectArgs("path.base", args, 1)
+		return filepath.Base(args[0])
+	},
+	"path.dir": func(args []string) string {
+		expectArgs("path.dir", args, 1)
+		return filepath.Dir(args[0])
+	},
+	"path.ext": func(args []string) string {
+		expectArgs("path.ext", args, 1)
+		return filepath.Ext(args[0])
+	},
+	"path.abs": func(args []string) string {
+		expectArgs("path.abs", args, 1)
+		abs, err := filepath.Abs(args[0])
+		if err != nil {
+			fail("path.abs: %v", err)
+		}
+		return abs
+	},
+	"os.cwd": func(args []string) string {
+		expectArgs("os.cwd", args, 0)
+		dir, err := os.Getwd()
+		if err != nil {
+			fail("os.cwd: %v", err)
+		}
+		return dir
+	},
+	"os.chdir": func(args []string) string {
+		expectArgs("os.chdir", args, 1)
+		if err := os.Chdir(args[0]); err != nil {
+			fail("os.chdir: %v", err)
+		}
+		return ""
+	},
+	"os.tempDir": func(args []string) string {
+		expectArgs("os.tempDir", args, 0)
+		return os.TempDir()
+	},
+	"os.hostname": func(args []string) string {
+		expectArgs("os.hostname", args, 0)
+		name, err := os.Hostname()
+		if err != nil {
+			fail("os.hostname: %v", err)
+		}
+		return name
+	},
+	"os.platform": func(args []string) string {
+		expectArgs("os.platform", args, 0)
+		return runtime.GOOS
+	},
+	// os.listDir returns one entry name per line since there are no array values yet
+	"os.listDir": func(args []string) string {
+		expectArgs("os.listDir", args, 1)
+		entries, err := os.ReadDir(args[0])
+		if err != nil {
+			fail("os.listDir: %v", err)
+		}
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			names[i] = entry.Name()
+		}
+		return strings.Join(names, "\n")
+	},
+	"uuid": func(args []string) string {
+		expectArgs("uuid", args, 0)
+		return newUUID()
+	},
+	"fmt.number": func(args []string) string {
+		expectArgs("fmt.number", args, 2)
+		value := parseNumber("fmt.number", args[0])
+		return message.NewPrinter(parseLocale(args[1])).Sprint(number.Decimal(value))
+	},
+	"fmt.currency": func(args []string) string {
+		expectArgs("fmt.currency", args, 3)
+		value := parseNumber("fmt.currency", args[0])
+		unit, err := currency.ParseISO(args[1])
+		if err != nil {
+			fail("fmt.currency: unknown currency %q", args[1])
+		}
+		return formatCurrency(value, unit, parseLocale(args[2]))
+	},
+	"fmt.compare": func(args []string) string {
+		expectArgs("fmt.compare", args, 3)
+		return strconv.Itoa(collate.New(parseLocale(args[2])).CompareString(args[0], args[1]))
+	},
+}
+
+// CLDR standard currency patterns as positive and negative pairs, with ¤ for
+// the currency symbol and # for the formatted amount. Regional entries are
+// only needed where a region differs from its language's default region
+var currencyPatterns = map[string][2]string{
+	"cs":    {"#\u00a0¤", "-#\u00a0¤"},
+	"da":    {"#\u00a0¤", "-#\u00a0¤"},
+	"de":    {"#\u00a0¤", "-#\u00a0¤"},
+	"de-AT": {"¤\u00a0#", "-¤\u00a0#"},
+	"de-CH": {"¤\u00a0#", "¤-#"},
+	"en":    {"¤#", "-¤#"},
+	"en-AU": {"¤#", "-¤#"},
+	"en-CA": {"¤#", "-¤#"},
+	"en-GB": {"¤#", "-¤#"},
+	"es":    {"#\u00a0¤", "-#\u00a0¤"},
+	"es-MX": {"¤#", "-¤#"},
+	"fr":    {"#\u00a0¤", "-#\u00a0¤"},
+	"fr-CA": {"#\u00a0¤", "-#\u00a0¤"},
+	"it":    {"#\u00a0¤", "-#\u00a0¤"},
+	"ja":    {"¤#", "-¤#"},
+	"ko":    {"¤#", "-¤#"},
+	"nl":    {"¤\u00a0#", "¤\u00a0-#"},
+	"pl":    {"#\u00a0¤", "-#\u00a0¤"},
+	"pt":    {"¤\u00a0#", "-¤\u00a0#"},
+	"pt-PT": {"#\u00a0¤", "-#\u00a0¤"},
+	"ru":    {"#\u00a0¤", "-#\u00a0¤"},
+	"sr":    {"#\u00a0¤", "-#\u00a0¤"},
+	"zh":    {"¤#", "-¤#"},
+}
+
+// currencyPattern returns the positive and negative currency patterns for a
+// locale, falling back to its language only when the locale names no region
+// or that language's default region
+func currencyPattern(tag language.Tag) ([2]string, bool) {
+	if patterns, ok := currencyPatterns[tag.String()]; ok {
+		return patterns, true
+	}
+
+	base, _ := tag.Base()
+	region, confidence := tag.Region()
+	defaultRegion, _ := language.Make(base.String()).Region()
+	if confidence == language.Exact && region != defaultRegion {
+		return [2]string{}, false
+	}
+
+	patterns, ok := currencyPatterns[base.String()]
+	return patterns, ok
+}
+
+// formatCurrency formats an amount with the currency's standard number of
+// decimals using the locale's CLDR currency pattern
+func formatCurrency(value float64, unit currency.Unit, tag language.Tag) string {
+	patterns, ok := currencyPattern(tag)
+	if !ok {
+		fail("fmt.currency: unsupported locale %q", tag.String())
+	}
+
+	pattern := patterns[0]
+	if value < 0 {
+		pattern = patterns[1]
+		value = -value
+	}
+
+	printer := message.NewPrinter(tag)
+	scale, _ := currency.Standard.Rounding(unit)
+	amount := printer.Sprint(number.Decimal(value, number.Scale(scale)))
+	symbol := printer.Sprint(currency.Symbol(unit))
+	return strings.NewReplacer("#", amount, "¤", symbol).Replace(pattern)
+}
+
+// parseNumber fails if a builtin was given an argument that is not a number
+func parseNumber(name string, arg string) float64 {
+	value, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		fail("%s expects a number, got %q", name, arg)
+	}
+	return value
+}
+
+// parseLocale fails if a builtin was given an invalid BCP 47 locale such as "en-US"
+func parseLocale(arg string) language.Tag {
+	tag, err := language.Parse(arg)
+	if err != nil {
+		fail("invalid locale %q", arg)
+	}
+	return tag
+}
+
+// newUUID returns an RFC 4122 version 4 UUID read from the random source
+func newUUID() string {
+	var b [16]byte
+	if _, err := io.ReadFull(randomSource, b[:]); err != nil {
+		fail("uuid: %v", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// expectArgs fails if a builtin was called with the wrong number of arguments
+func expectArgs(name string, args []string, count int) {
+	if len(args) != count {
+		fail("%s expects %d argument(s), got %d", name, count, len(args))
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Name of the per-project configuration file
+const configFileName = ".esrc"
+
+// Environment variables that override flag defaults, keyed by flag name
+var flagEnvVars = map[string]string{
+	"log-level":      "ES_LOG_LEVEL",
+	"log-timestamps": "ES_LOG_TIMESTAMPS",
+}
+
+// configure fills in flags that were not given on the command line, first from
+// the nearest .esrc above the script and then from environment variables
+func configure(scriptPath string) error {
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	configPath, err := findConfig(scriptPath)
+	if err != nil {
+		return err
+	}
+	if configPath != "" {
+		settings, err := loadConfig(configPath)
+		if err != nil {
+			return err
+		}
+		for name, value := range settings {
+			if flag.Lookup(name) == nil {
+				return fmt.Errorf("%s: unknown setting %q", configPath, name)
+			}
+			if explicit[name] {
+				continue
+			}
+			if err := flag.Set(name, fmt.Sprint(value)); err != nil {
+				return fmt.Errorf("%s: invalid value for %q: %v", configPath, name, err)
+			}
+		}
+	}
+
+	for name, envVar := range flagEnvVars {
+		value := os.Getenv(envVar)
+		if value == "" || explicit[name] {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("%s: invalid value %q: %v", envVar, value, err)
+		}
+	}
+
+	return nil
+}
+
+// findConfig returns the path of the .esrc in the script's directory or the
+// closest parent directory, or an empty string if there is none
+func findConfig(scriptPath string) (string, error) {
+	dir, err := filepath.Abs(filepath.Dir(scriptPath))
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		path := filepath.Join(dir, configFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// loadConfig reads a .esrc file mapping flag names to their values. Files
+// whose content starts with "{" are parsed as JSON, anything else as TOML
+func loadConfig(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]interface{}{}
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		if _, err := toml.Decode(string(data), &settings); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return settings, nil
+	}
+
+	// Keep numbers as their literal digits so large integers reach flag.Set intact
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&settings); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return settings, nil
+}
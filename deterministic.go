@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	mathrand "math/rand"
+	"time"
+)
+
+// Sources of nondeterminism used by builtins, replaced in deterministic mode
+var (
+	randomSource io.Reader = rand.Reader
+	now                    = time.Now
+)
+
+// Deterministic mode configuration, set from flags or .esrc
+var (
+	deterministic = false
+	seed          int64
+)
+
+// enableDeterministicMode seeds the random source and freezes the clock at a
+// fixed instant that advances by one second on every reading, so that a
+// script produces the same output on every run
+func enableDeterministicMode(randomSeed int64) {
+	randomSource = &seededReader{rng: mathrand.New(mathrand.NewSource(randomSeed))}
+
+	clock := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		current := clock
+		clock = clock.Add(time.Second)
+		return current
+	}
+}
+
+// seededReader fills byte slices from a seeded math/rand generator, eight
+// bytes per Uint64 call, since (*rand.Rand).Read is deprecated
+type seededReader struct {
+	rng *mathrand.Rand
+}
+
+// Read for seededReader
+func (r *seededReader) Read(p []byte) (int, error) {
+	var buf [8]byte
+	for i := 0; i < len(p); i += len(buf) {
+		binary.LittleEndian.PutUint64(buf[:], r.rng.Uint64())
+		copy(p[i:], buf[:])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runDiff structurally compares the statements of two programs and returns the
+// exit code, which like diff(1) is 1 when the programs differ
+func runDiff(args []string) int {
+	if len(args) != 2 {
+		fmt.Println("Please provide two files to compare: diff old.es new.es")
+		return 2
+	}
+
+	oldStatements, err := readStatements(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	newStatements, err := readStatements(args[1])
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+
+	added, removed, changed := 0, 0, 0
+	for _, edit := range pairChanges(diffStatements(oldStatements, newStatements)) {
+		switch edit.Kind {
+		case '~':
+			fmt.Printf("~ %d: %s\n  => %d: %s\n", edit.Old+1, oldStatements[edit.Old], edit.New+1, newStatements[edit.New])
+			changed++
+		case '-':
+			fmt.Printf("- %d: %s\n", edit.Old+1, oldStatements[edit.Old])
+			removed++
+		case '+':
+			fmt.Printf("+ %d: %s\n", edit.New+1, newStatements[edit.New])
+			added++
+		}
+	}
+
+	if added+removed+changed == 0 {
+		return 0
+	}
+	fmt.Printf("\n%d added, %d removed, %d changed\n", added, removed, changed)
+	return 1
+}
+
+// readStatements parses a .es file and returns its statements in canonical form
+func readStatements(fileName string) (statements []string, err error) {
+	// Lex and Parse panic with a SyntaxError on invalid programs
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxErr, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			statements, err = nil, fmt.Errorf("%s: %v", fileName, syntaxErr)
+		}
+	}()
+
+	if !strings.HasSuffix(fileName, ".es") {
+		return nil, fmt.Errorf("Unsupported file type %s. Please provide .es files to compare", fileName)
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	ast := Parse(Lex(string(data)))
+	statements = make([]string, len(ast))
+	for i, node := range ast {
+		statements[i] = FormatNode(node)
+	}
+	return statements, nil
+}
+
+// Edit is one step of a statement diff: '=' keeps, '-' removes, '+' adds and '~' changes a statement
+type Edit struct {
+	Kind byte
+	Old  int
+	New  int
+}
+
+// diffStatements computes the edit script between two statement lists using their longest common subsequence
+func diffStatements(a, b []string) []Edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	edits := []Edit{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			edits = append(edits, Edit{Kind: '=', Old: i, New: j})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			edits = append(edits, Edit{Kind: '-', Old: i, New: j})
+			i++
+		} else {
+			edits = append(edits, Edit{Kind: '+', Old: i, New: j})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, Edit{Kind: '-', Old: i, New: j})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, Edit{Kind: '+', Old: i, New: j})
+	}
+	return edits
+}
+
+// pairChanges turns removals and additions at the same place into changes,
+// pairing them in order and leaving any surplus as plain removals or additions
+func pairChanges(edits []Edit) []Edit {
+	paired := []Edit{}
+	var removedRun, addedRun []Edit
+	flush := func() {
+		for len(removedRun) > 0 && len(addedRun) > 0 {
+			paired = append(paired, Edit{Kind: '~', Old: removedRun[0].Old, New: addedRun[0].New})
+			removedRun, addedRun = removedRun[1:], addedRun[1:]
+		}
+		paired = append(paired, removedRun...)
+		paired = append(paired, addedRun...)
+		removedRun, addedRun = nil, nil
+	}
+
+	for _, edit := range edits {
+		switch edit.Kind {
+		case '-':
+			removedRun = append(removedRun, edit)
+		case '+':
+			addedRun = append(addedRun, edit)
+		default:
+			flush()
+			paired = append(paired, edit)
+		}
+	}
+	flush()
+	return paired
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatNode serializes a node back into canonical easy-script source, so that
+// programs differing only in whitespace or formatting produce the same text
+func FormatNode(node Node) string {
+	switch n := node.(type) {
+	case *ConsoleLogNode:
+		return "console.log(" + formatArguments(n.Arguments) + ")"
+	case *LogNode:
+		return "log." + n.Level + "(" + formatArguments(n.Arguments) + ")"
+	case *AssertNode:
+		args := []Node{n.Condition}
+		if n.Message != nil {
+			args = append(args, n.Message)
+		}
+		return "assert(" + formatArguments(args) + ")"
+	case *CallNode:
+		return n.Name + "(" + formatArguments(n.Arguments) + ")"
+	case *StringNode:
+		return "\"" + n.Value + "\""
+	case *IntNode:
+		return n.Value
+	case *PlusNode:
+		return FormatNode(n.Left) + " + " + FormatNode(n.Right)
+	case *MinusNode:
+		return FormatNode(n.Left) + " - " + FormatNode(n.Right)
+	case *MultiplyNode:
+		return FormatNode(n.Left) + " * " + FormatNode(n.Right)
+	case *DivideNode:
+		return FormatNode(n.Left) + " / " + FormatNode(n.Right)
+	case *ModuloNode:
+		return FormatNode(n.Left) + " % " + FormatNode(n.Right)
+	case *PowerNode:
+		return FormatNode(n.Left) + " ^ " + FormatNode(n.Right)
+	}
+	panic(fmt.Sprintf("Cannot format %T", node))
+}
+
+// formatArguments serializes a comma separated argument list
+func formatArguments(args []Node) string {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		formatted[i] = FormatNode(arg)
+	}
+	return strings.Join(formatted, ", ")
+}
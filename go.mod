@@ -1,3 +1,8 @@
 module github.com/anik-ghosh-au7/easy-script
 
 go 1.20
+
+require (
+	github.com/BurntSushi/toml v1.2.0
+	golang.org/x/text v0.14.0
+)
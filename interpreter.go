@@ -1,17 +1,28 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
 )
 
 // Defines different types of tokens
 const (
 	TokenConsole  = "CONSOLE"
 	TokenLog      = "LOG"
+	TokenLevel    = "LEVEL"
+	TokenAssert   = "ASSERT"
+	TokenCall     = "CALL"
+	TokenCallStmt = "CALL_STATEMENT"
+	TokenRParen   = "RPAREN"
 	TokenString   = "STRING"
 	TokenInt      = "INT"
 	TokenPlus     = "PLUS"
@@ -22,6 +33,23 @@ const (
 	TokenPower    = "POWER"
 )
 
+// Log levels in increasing order of severity
+var logLevels = map[string]int{
+	"debug": 0,
+	"info":  1,
+	"warn":  2,
+	"error": 3,
+}
+
+// Logging configuration, set from flags, environment variables or .esrc
+var (
+	logLevel      = "info"
+	logTimestamps = false
+)
+
+// Set once Ctrl-C is pressed while a script is running
+var interrupted atomic.Bool
+
 // Token struct
 type Token struct {
 	Type    string
@@ -47,6 +75,87 @@ func (n *ConsoleLogNode) Execute() string {
 	return strings.Join(args, " ")
 }
 
+// Node type for log.<level> statements
+type LogNode struct {
+	Level     string
+	Arguments []Node
+}
+
+// Enabled reports whether the statement passes the configured level filter
+func (n *LogNode) Enabled() bool {
+	return logLevels[n.Level] >= logLevels[logLevel]
+}
+
+// Execute for LogNode
+func (n *LogNode) Execute() string {
+	args := make([]string, len(n.Arguments))
+	for i, arg := range n.Arguments {
+		args[i] = arg.Execute()
+	}
+
+	line := "[" + strings.ToUpper(n.Level) + "] " + strings.Join(args, " ")
+	if logTimestamps {
+		line = now().Format(time.RFC3339) + " " + line
+	}
+	return line
+}
+
+// Node type for calls to builtin functions
+type CallNode struct {
+	Name      string
+	Arguments []Node
+}
+
+// Execute for CallNode
+func (n *CallNode) Execute() string {
+	args := make([]string, len(n.Arguments))
+	for i, arg := range n.Arguments {
+		args[i] = arg.Execute()
+	}
+	return builtins[n.Name](args)
+}
+
+// Node type for assert statements
+type AssertNode struct {
+	Condition Node
+	Message   Node
+}
+
+// Execute for AssertNode
+func (n *AssertNode) Execute() string {
+	// Conditions are falsy when they evaluate to an empty string or zero
+	result := n.Condition.Execute()
+	if result != "" && result != "0" {
+		return ""
+	}
+
+	message := "assertion failed"
+	if n.Message != nil {
+		message = n.Message.Execute()
+	}
+	panic(&AssertionError{Message: message})
+}
+
+// AssertionError is the panic value raised by a failing assert statement
+type AssertionError struct {
+	Message string
+}
+
+// Error for AssertionError
+func (e *AssertionError) Error() string {
+	return "Assertion failed: " + e.Message
+}
+
+// SyntaxError is the panic value raised by Lex and Parse for invalid programs
+type SyntaxError struct {
+	Message string
+}
+
+// Error for SyntaxError
+func (e *SyntaxError) Error() string {
+	return e.Message
+}
+
 // Node type for string literals
 type StringNode struct {
 	Value string
@@ -159,137 +268,373 @@ func Lex(input string) []Token {
 
 		startIndex := strings.Index(stmt, "(")
 		endIndex := strings.LastIndex(stmt, ")")
+		if startIndex == -1 || endIndex < startIndex {
+			panic(&SyntaxError{Message: fmt.Sprintf("Invalid syntax: expected a call such as console.log(...) but found %q", stmt)})
+		}
+
+		// Builtin calls such as os.chdir("..") can also be used as statements
+		if _, ok := builtins[strings.TrimSpace(stmt[:startIndex])]; ok {
+			call := lexArguments(stmt)
+			call[0].Type = TokenCallStmt
+			tokens = append(tokens, call...)
+			continue
+		}
 
 		consoleLog := strings.FieldsFunc(stmt[:startIndex], func(r rune) bool {
 			return r == ' ' || r == '.'
 		})
-		arguments := strings.Split(stmt[startIndex+1:endIndex], ",")
 
 		for _, word := range consoleLog {
 			if word == "console" {
 				tokens = append(tokens, Token{Type: TokenConsole, Literal: word})
 			} else if word == "log" {
 				tokens = append(tokens, Token{Type: TokenLog, Literal: word})
+			} else if _, ok := logLevels[word]; ok {
+				tokens = append(tokens, Token{Type: TokenLevel, Literal: word})
+			} else if word == "assert" {
+				tokens = append(tokens, Token{Type: TokenAssert, Literal: word})
 			}
 		}
 
-		for _, arg := range arguments {
-			arg = strings.TrimSpace(arg)
-			if strings.HasPrefix(arg, "\"") && strings.HasSuffix(arg, "\"") {
-				tokens = append(tokens, Token{Type: TokenString, Literal: arg[1 : len(arg)-1]})
-			} else if strings.ContainsAny(arg, "+-*%/^") {
-				operatorIndex := strings.IndexAny(arg, "+-*%/^")
-				num1 := strings.TrimSpace(arg[:operatorIndex])
-				operator := strings.TrimSpace(arg[operatorIndex : operatorIndex+1])
-				num2 := strings.TrimSpace(arg[operatorIndex+1:])
-				tokens = append(tokens, Token{Type: TokenInt, Literal: num1})
-				switch operator {
-				case "+":
-					tokens = append(tokens, Token{Type: TokenPlus, Literal: operator})
-				case "-":
-					tokens = append(tokens, Token{Type: TokenMinus, Literal: operator})
-				case "*":
-					tokens = append(tokens, Token{Type: TokenMultiply, Literal: operator})
-				case "/":
-					tokens = append(tokens, Token{Type: TokenDivide, Literal: operator})
-				case "%":
-					tokens = append(tokens, Token{Type: TokenModulo, Literal: operator})
-				case "^":
-					tokens = append(tokens, Token{Type: TokenPower, Literal: operator})
-				}
-				tokens = append(tokens, Token{Type: TokenInt, Literal: num2})
-			} else {
-				tokens = append(tokens, Token{Type: TokenInt, Literal: arg})
+		tokens = append(tokens, lexArguments(stmt[startIndex+1:endIndex])...)
+	}
+
+	return tokens
+}
+
+// lexArguments converts a comma separated argument list into tokens
+func lexArguments(input string) []Token {
+	tokens := []Token{}
+
+	for _, arg := range splitArguments(input) {
+		arg = strings.TrimSpace(arg)
+		if strings.HasPrefix(arg, "\"") && strings.HasSuffix(arg, "\"") {
+			tokens = append(tokens, Token{Type: TokenString, Literal: arg[1 : len(arg)-1]})
+		} else if name, inner, ok := splitCall(arg); ok {
+			tokens = append(tokens, Token{Type: TokenCall, Literal: name})
+			tokens = append(tokens, lexArguments(inner)...)
+			tokens = append(tokens, Token{Type: TokenRParen, Literal: ")"})
+		} else if strings.ContainsAny(arg, "+-*%/^") {
+			operatorIndex := strings.IndexAny(arg, "+-*%/^")
+			num1 := strings.TrimSpace(arg[:operatorIndex])
+			operator := strings.TrimSpace(arg[operatorIndex : operatorIndex+1])
+			num2 := strings.TrimSpace(arg[operatorIndex+1:])
+			tokens = append(tokens, Token{Type: TokenInt, Literal: num1})
+			switch operator {
+			case "+":
+				tokens = append(tokens, Token{Type: TokenPlus, Literal: operator})
+			case "-":
+				tokens = append(tokens, Token{Type: TokenMinus, Literal: operator})
+			case "*":
+				tokens = append(tokens, Token{Type: TokenMultiply, Literal: operator})
+			case "/":
+				tokens = append(tokens, Token{Type: TokenDivide, Literal: operator})
+			case "%":
+				tokens = append(tokens, Token{Type: TokenModulo, Literal: operator})
+			case "^":
+				tokens = append(tokens, Token{Type: TokenPower, Literal: operator})
 			}
+			tokens = append(tokens, Token{Type: TokenInt, Literal: num2})
+		} else {
+			tokens = append(tokens, Token{Type: TokenInt, Literal: arg})
 		}
 	}
 
 	return tokens
 }
 
+// splitArguments splits an argument list on commas that are not inside a string or nested call
+func splitArguments(input string) []string {
+	if strings.TrimSpace(input) == "" {
+		return nil
+	}
+
+	arguments := []string{}
+	inString := false
+	depth := 0
+	start := 0
+	for i, r := range input {
+		switch {
+		case r == '"':
+			inString = !inString
+		case inString:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth < 0 {
+				panic(&SyntaxError{Message: fmt.Sprintf("Invalid syntax: unbalanced parentheses in %q", input)})
+			}
+		case r == ',' && depth == 0:
+			arguments = append(arguments, input[start:i])
+			start = i + 1
+		}
+	}
+
+	if inString {
+		panic(&SyntaxError{Message: fmt.Sprintf("Invalid syntax: unterminated string in %q", input)})
+	}
+	if depth != 0 {
+		panic(&SyntaxError{Message: fmt.Sprintf("Invalid syntax: unbalanced parentheses in %q", input)})
+	}
+	return append(arguments, input[start:])
+}
+
+// splitCall splits a call expression such as path.base("a/b") into its name and argument list
+func splitCall(arg string) (string, string, bool) {
+	openIndex := strings.Index(arg, "(")
+	if openIndex <= 0 || !strings.HasSuffix(arg, ")") {
+		return "", "", false
+	}
+
+	name := arg[:openIndex]
+	for i, r := range name {
+		if !(r == '_' || r == '.' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return "", "", false
+		}
+	}
+
+	return name, arg[openIndex+1 : len(arg)-1], true
+}
+
 // Parse function to convert the tokens into AST nodes
 func Parse(tokens []Token) []Node {
 	nodes := []Node{}
 
 	i := 0
 	for i < len(tokens) {
-		if tokens[i].Type == TokenConsole && tokens[i+1].Type == TokenLog {
-			i += 2
-
-			args := []Node{}
-			for i < len(tokens) && tokens[i].Type != TokenConsole {
-				if tokens[i].Type == TokenString {
-					args = append(args, &StringNode{Value: tokens[i].Literal})
-				} else if tokens[i].Type == TokenInt {
-					if i+2 < len(tokens) && tokens[i+2].Type == TokenInt {
-						switch tokens[i+1].Type {
-						case TokenPlus:
-							args = append(args, &PlusNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
-						case TokenMinus:
-							args = append(args, &MinusNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
-						case TokenMultiply:
-							args = append(args, &MultiplyNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
-						case TokenDivide:
-							args = append(args, &DivideNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
-						case TokenModulo:
-							args = append(args, &ModuloNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
-						case TokenPower:
-							args = append(args, &PowerNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
-						}
-						i += 2
-					} else {
-						args = append(args, &IntNode{Value: tokens[i].Literal})
-					}
-				}
-				i++
-			}
+		if tokens[i].Type == TokenConsole && i+1 < len(tokens) && tokens[i+1].Type == TokenLog {
+			args, next := parseArguments(tokens, i+2)
+			i = next
 
 			nodes = append(nodes, &ConsoleLogNode{Arguments: args})
+		} else if tokens[i].Type == TokenLog && i+1 < len(tokens) && tokens[i+1].Type == TokenLevel {
+			args, next := parseArguments(tokens, i+2)
+			nodes = append(nodes, &LogNode{Level: tokens[i+1].Literal, Arguments: args})
+			i = next
+		} else if tokens[i].Type == TokenAssert {
+			args, next := parseArguments(tokens, i+1)
+			i = next
+
+			if len(args) == 0 || len(args) > 2 {
+				panic(&SyntaxError{Message: "Invalid syntax"})
+			}
+			assert := &AssertNode{Condition: args[0]}
+			if len(args) == 2 {
+				assert.Message = args[1]
+			}
+			nodes = append(nodes, assert)
+		} else if tokens[i].Type == TokenCallStmt {
+			call, next := parseCall(tokens, i)
+			i = next
+
+			nodes = append(nodes, call)
 		} else {
-			panic("Invalid syntax")
+			panic(&SyntaxError{Message: "Invalid syntax"})
 		}
 	}
 
 	return nodes
 }
 
-// Eval function to take a slice of nodes (AST) and evaluate them
-func Eval(nodes []Node) {
-	for _, node := range nodes {
-		fmt.Println(node.Execute())
+// parseCall converts the call starting at i into a node and returns the index of the token after its closing parenthesis
+func parseCall(tokens []Token, i int) (*CallNode, int) {
+	name := tokens[i].Literal
+	if _, ok := builtins[name]; !ok {
+		panic(&SyntaxError{Message: "Unknown function: " + name})
+	}
+
+	args, next := parseArguments(tokens, i+1)
+	if next >= len(tokens) || tokens[next].Type != TokenRParen {
+		panic(&SyntaxError{Message: "Invalid syntax"})
+	}
+
+	return &CallNode{Name: name, Arguments: args}, next + 1
+}
+
+// isStatementStart reports whether the token begins a new statement
+func isStatementStart(token Token) bool {
+	return token.Type == TokenConsole || token.Type == TokenLog || token.Type == TokenAssert || token.Type == TokenCallStmt
+}
+
+// isOperator reports whether the token is an arithmetic operator
+func isOperator(token Token) bool {
+	switch token.Type {
+	case TokenPlus, TokenMinus, TokenMultiply, TokenDivide, TokenModulo, TokenPower:
+		return true
+	}
+	return false
+}
+
+// parseArguments converts the argument tokens starting at i into nodes and returns the index of the first token after them
+func parseArguments(tokens []Token, i int) ([]Node, int) {
+	args := []Node{}
+	for i < len(tokens) && !isStatementStart(tokens[i]) && tokens[i].Type != TokenRParen {
+		if tokens[i].Type == TokenString {
+			args = append(args, &StringNode{Value: tokens[i].Literal})
+		} else if tokens[i].Type == TokenCall {
+			call, next := parseCall(tokens, i)
+			args = append(args, call)
+			i = next
+			continue
+		} else if tokens[i].Type == TokenInt {
+			if i+2 < len(tokens) && isOperator(tokens[i+1]) && tokens[i+2].Type == TokenInt {
+				switch tokens[i+1].Type {
+				case TokenPlus:
+					args = append(args, &PlusNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
+				case TokenMinus:
+					args = append(args, &MinusNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
+				case TokenMultiply:
+					args = append(args, &MultiplyNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
+				case TokenDivide:
+					args = append(args, &DivideNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
+				case TokenModulo:
+					args = append(args, &ModuloNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
+				case TokenPower:
+					args = append(args, &PowerNode{Left: &IntNode{Value: tokens[i].Literal}, Right: &IntNode{Value: tokens[i+2].Literal}})
+				}
+				i += 2
+			} else {
+				args = append(args, &IntNode{Value: tokens[i].Literal})
+			}
+		}
+		i++
+	}
+
+	return args, i
+}
+
+// Eval function to take a slice of nodes (AST) and evaluate them, writing the output to w
+func Eval(w io.Writer, nodes []Node) {
+	current := -1
+	for i, node := range nodes {
+		checkInterrupt(nodes, current)
+		current = i
+		output := node.Execute()
+		switch node.(type) {
+		case *AssertNode, *CallNode:
+			continue
+		}
+		if log, ok := node.(*LogNode); ok && !log.Enabled() {
+			continue
+		}
+		fmt.Fprintln(w, output)
+	}
+	checkInterrupt(nodes, current)
+}
+
+// watchInterrupts records the first Ctrl-C so the interpreter can stop at the
+// next statement boundary, and restores the default handler so that a second
+// Ctrl-C kills a script stuck inside a statement
+func watchInterrupts() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		interrupted.Store(true)
+	}()
+}
+
+// checkInterrupt exits with code 130 if Ctrl-C was pressed, reporting the
+// statement at index as the one that was running, if any
+func checkInterrupt(nodes []Node, index int) {
+	if !interrupted.Load() {
+		return
+	}
+
+	fmt.Println("\nInterrupted")
+	if index >= 0 {
+		fmt.Printf("  in statement %d: %s\n", index+1, FormatNode(nodes[index]))
 	}
+	os.Exit(130)
 }
 
 // Main function to read the content of a .es file and pass it to the lexer, parser, and finally to the evaluator
 func main() {
-	if len(os.Args) < 2 {
+	flag.StringVar(&logLevel, "log-level", "info", "minimum level for log statements: debug, info, warn or error (env ES_LOG_LEVEL)")
+	flag.BoolVar(&logTimestamps, "log-timestamps", false, "prefix log statements with a timestamp (env ES_LOG_TIMESTAMPS)")
+	flag.BoolVar(&deterministic, "deterministic", false, "make uuid() reproducible and freeze the clock used for log timestamps")
+	flag.Int64Var(&seed, "seed", 0, "random seed for deterministic mode; implies --deterministic")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
 		fmt.Println("Please provide a file to execute")
 		os.Exit(1)
 	}
 
-	fileName := os.Args[1]
+	switch flag.Arg(0) {
+	case "learn":
+		os.Exit(runLearn(flag.Args()[1:]))
+	case "diff":
+		os.Exit(runDiff(flag.Args()[1:]))
+	}
+
+	if flag.NArg() > 1 {
+		fmt.Printf("Unexpected arguments after %s: %s\n", flag.Arg(0), strings.Join(flag.Args()[1:], " "))
+		fmt.Println("Usage: easy-script [flags] file.es (flags must come before the file)")
+		os.Exit(1)
+	}
+
+	fileName := flag.Arg(0)
 	if !strings.HasSuffix(fileName, ".es") {
 		fmt.Println("Unsupported file type. Please provide a .es file to execute")
 		os.Exit(1)
 	}
 
+	if err := configure(fileName); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if _, ok := logLevels[logLevel]; !ok {
+		fmt.Println("Unknown log level:", logLevel)
+		os.Exit(1)
+	}
+
+	// Giving a seed from the command line or .esrc turns on deterministic mode
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			deterministic = true
+		}
+	})
+	if deterministic {
+		enableDeterministicMode(seed)
+	}
+
+	watchInterrupts()
+
 	data, err := os.ReadFile(fileName)
 	if err != nil {
 		panic(err)
 	}
 
+	defer func() {
+		if r := recover(); r != nil {
+			switch err := r.(type) {
+			case *AssertionError, *SyntaxError, *ScriptError:
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			panic(r)
+		}
+	}()
+
+	// Nothing has run before Eval, so interrupts there name no statement
 	tokens := Lex(string(data))
 	fmt.Println("Tokens:")
 	for _, token := range tokens {
+		checkInterrupt(nil, -1)
 		fmt.Printf("Type: %s, Literal: %s\n", token.Type, token.Literal)
 	}
 
 	ast := Parse(tokens)
 	fmt.Println("\nAbstract Syntax Tree:")
 	for _, node := range ast {
-		fmt.Printf("%T: %s\n", node, node.Execute())
+		checkInterrupt(nil, -1)
+		fmt.Printf("%T: %s\n", node, FormatNode(node))
 	}
 
 	fmt.Println("\nOutput:")
-	Eval(ast)
+	Eval(os.Stdout, ast)
 }
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+)
+
+// expectSyntaxError fails the test unless f panics with a SyntaxError
+func expectSyntaxError(t *testing.T, f func()) {
+	t.Helper()
+	defer func() {
+		if _, ok := recover().(*SyntaxError); !ok {
+			t.Errorf("expected a SyntaxError panic")
+		}
+	}()
+	f()
+}
+
+func TestSplitArguments(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{``, nil},
+		{`"a", 1`, []string{`"a"`, ` 1`}},
+		{`"a, b", 2`, []string{`"a, b"`, ` 2`}},
+		{`"(", ")"`, []string{`"("`, ` ")"`}},
+		{`path.join("a", "b"), 3`, []string{`path.join("a", "b")`, ` 3`}},
+		{`path.base(path.dir("a,b")), "x"`, []string{`path.base(path.dir("a,b"))`, ` "x"`}},
+	}
+
+	for _, test := range tests {
+		got := splitArguments(test.input)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitArguments(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestSplitArgumentsInvalid(t *testing.T) {
+	for _, input := range []string{`path.base("a"`, `"a")`, `a), (b`, `"abc`} {
+		expectSyntaxError(t, func() { splitArguments(input) })
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{`console.log("a, b", 1 + 2);`, []string{`console.log("a, b", 1 + 2)`}},
+		{`console.log(1, 2, 3);`, []string{`console.log(1, 2, 3)`}},
+		{`console.log(path.base(path.dir("/a/b/c")), 2 ^ 3);`, []string{`console.log(path.base(path.dir("/a/b/c")), 2 ^ 3)`}},
+		{`console.log(path.join("a", "b, c"));`, []string{`console.log(path.join("a", "b, c"))`}},
+		{`console.log(os.cwd());`, []string{`console.log(os.cwd())`}},
+		{"os.chdir(\"/tmp\");\nconsole.log(\"x\");", []string{`os.chdir("/tmp")`, `console.log("x")`}},
+		{`log.warn("low", 5); assert(1 - 1, "zero");`, []string{`log.warn("low", 5)`, `assert(1 - 1, "zero")`}},
+	}
+
+	for _, test := range tests {
+		nodes := Parse(Lex(test.source))
+		got := make([]string, len(nodes))
+		for i, node := range nodes {
+			got[i] = FormatNode(node)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Parse(Lex(%q)) = %q, want %q", test.source, got, test.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	sources := []string{
+		`foo;`,
+		`console.log(path.base("a");`,
+		`console.log("a"));`,
+		`console.log(nope.fn());`,
+		`assert();`,
+	}
+
+	for _, source := range sources {
+		expectSyntaxError(t, func() { Parse(Lex(source)) })
+	}
+}
+
+func TestPairChanges(t *testing.T) {
+	tests := []struct {
+		old, new []string
+		want     []Edit
+	}{
+		{
+			[]string{"a", "b"}, []string{"a", "b"},
+			[]Edit{{'=', 0, 0}, {'=', 1, 1}},
+		},
+		{
+			[]string{"a", "b", "c"}, []string{"a", "x", "c"},
+			[]Edit{{'=', 0, 0}, {'~', 1, 1}, {'=', 2, 2}},
+		},
+		{
+			[]string{"a", "b"}, []string{"a", "b", "c"},
+			[]Edit{{'=', 0, 0}, {'=', 1, 1}, {'+', 2, 2}},
+		},
+		{
+			[]string{"a", "b", "c"}, []string{"c"},
+			[]Edit{{'-', 0, 0}, {'-', 1, 0}, {'=', 2, 0}},
+		},
+		{
+			[]string{"a", "b"}, []string{"x", "y", "z"},
+			[]Edit{{'~', 0, 0}, {'~', 1, 1}, {'+', 2, 2}},
+		},
+		{
+			[]string{"a", "b", "c", "d"}, []string{"b", "x", "d", "e"},
+			[]Edit{{'-', 0, 0}, {'=', 1, 0}, {'~', 2, 1}, {'=', 3, 2}, {'+', 4, 3}},
+		},
+	}
+
+	for _, test := range tests {
+		got := pairChanges(diffStatements(test.old, test.new))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("pairChanges(diffStatements(%q, %q)) = %v, want %v", test.old, test.new, got, test.want)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			"json",
+			`{"log-level": "warn", "deterministic": true, "seed": 9223372036854775807}`,
+			map[string]string{"log-level": "warn", "deterministic": "true", "seed": "9223372036854775807"},
+		},
+		{
+			"json with leading whitespace",
+			"\n  {\"seed\": 1000000}",
+			map[string]string{"seed": "1000000"},
+		},
+		{
+			"toml",
+			"# defaults\nlog-level = \"debug\"\nlog-timestamps = true\nseed = 42\n",
+			map[string]string{"log-level": "debug", "log-timestamps": "true", "seed": "42"},
+		},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), configFileName)
+		if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		settings, err := loadConfig(path)
+		if err != nil {
+			t.Errorf("%s: loadConfig returned %v", test.name, err)
+			continue
+		}
+		got := map[string]string{}
+		for name, value := range settings {
+			got[name] = fmt.Sprint(value)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: loadConfig = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	for _, content := range []string{`{"log-level": }`, "log-level = \n"} {
+		path := filepath.Join(t.TempDir(), configFileName)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadConfig(path); err == nil {
+			t.Errorf("loadConfig(%q) succeeded, want an error", content)
+		}
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		value  float64
+		unit   string
+		locale string
+		want   string
+	}{
+		{1234.5, "USD", "en-US", "$1,234.50"},
+		{-1234.5, "USD", "en-US", "-$1,234.50"},
+		{1234.5, "EUR", "de-DE", "1.234,50\u00a0€"},
+		{-1234.5, "EUR", "de-DE", "-1.234,50\u00a0€"},
+		{1234.5, "EUR", "nl", "€\u00a01.234,50"},
+		{-5, "EUR", "nl", "€\u00a0-5,00"},
+		{-5, "EUR", "sr", "-5,00\u00a0€"},
+		{1234.5, "JPY", "ja", "\uffe51,234"},
+	}
+
+	for _, test := range tests {
+		got := formatCurrency(test.value, currency.MustParseISO(test.unit), language.MustParse(test.locale))
+		if got != test.want {
+			t.Errorf("formatCurrency(%v, %s, %s) = %q, want %q", test.value, test.unit, test.locale, got, test.want)
+		}
+	}
+}
+
+func TestFormatCurrencyUnsupportedLocale(t *testing.T) {
+	for _, locale := range []string{"is", "es-AR"} {
+		func() {
+			defer func() {
+				if _, ok := recover().(*ScriptError); !ok {
+					t.Errorf("formatCurrency with locale %s: expected a ScriptError panic", locale)
+				}
+			}()
+			formatCurrency(5, currency.EUR, language.MustParse(locale))
+		}()
+	}
+}
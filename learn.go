@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Lesson is an example program shipped with the interpreter for the learn command
+type Lesson struct {
+	Title       string
+	Explanation string
+	Source      string
+	Expected    string
+}
+
+// Lessons in order of increasing difficulty
+var lessons = []Lesson{
+	{
+		Title:       "Printing text",
+		Explanation: "console.log prints its arguments followed by a newline. Strings are written in double quotes and every statement ends with a semicolon.",
+		Source:      "console.log(\"Hello, world!\");\nconsole.log(\"Welcome to easy-script\");\n",
+		Expected:    "Hello, world!\nWelcome to easy-script\n",
+	},
+	{
+		Title:       "Numbers and multiple arguments",
+		Explanation: "console.log accepts any number of comma separated arguments and joins them with a single space. Integers are written without quotes.",
+		Source:      "console.log(\"The answer is\", 42);\nconsole.log(1, 2, 3);\n",
+		Expected:    "The answer is 42\n1 2 3\n",
+	},
+	{
+		Title:       "Arithmetic",
+		Explanation: "Each argument can be a single operation between two integers: + - * / % and ^ for powers. Division discards the remainder.",
+		Source:      "console.log(\"7 + 3 =\", 7 + 3);\nconsole.log(\"7 / 3 =\", 7 / 3);\nconsole.log(\"7 % 3 =\", 7 % 3);\nconsole.log(\"2 ^ 10 =\", 2 ^ 10);\n",
+		Expected:    "7 + 3 = 10\n7 / 3 = 2\n7 % 3 = 1\n2 ^ 10 = 1024\n",
+	},
+	{
+		Title:       "Assertions",
+		Explanation: "assert(condition, \"message\") stops the script with the message when the condition is zero or an empty string, and prints nothing when it holds.",
+		Source:      "assert(2 * 21, \"2 * 21 should not be zero\");\nconsole.log(\"all checks passed\");\n",
+		Expected:    "all checks passed\n",
+	},
+	{
+		Title:       "Log levels",
+		Explanation: "log.debug, log.info, log.warn and log.error print a message tagged with its level. Levels below --log-level (info by default) are hidden.",
+		Source:      "log.debug(\"hidden by default\");\nlog.info(\"starting up\");\nlog.error(\"something failed:\", 404);\n",
+		Expected:    "[INFO] starting up\n[ERROR] something failed: 404\n",
+	},
+	{
+		Title:       "Builtin functions",
+		Explanation: "Builtins such as path.base and path.ext can be called inside arguments, and calls can be nested.",
+		Source:      "console.log(\"file:\", path.base(\"/tmp/report.csv\"));\nconsole.log(\"type:\", path.ext(path.base(\"/tmp/report.csv\")));\n",
+		Expected:    "file: report.csv\ntype: .csv\n",
+	},
+}
+
+// runLearn runs every lesson, or only the one numbered in args, and returns the exit code
+func runLearn(args []string) int {
+	// Lessons expect the default logging configuration
+	logLevel = "info"
+	logTimestamps = false
+
+	selected := lessons
+	start := 1
+	if len(args) > 0 {
+		index, err := strconv.Atoi(args[0])
+		if err != nil || index < 1 || index > len(lessons) {
+			fmt.Printf("Unknown lesson %q. Please choose a lesson between 1 and %d\n", args[0], len(lessons))
+			return 1
+		}
+		selected = lessons[index-1 : index]
+		start = index
+	}
+
+	failed := 0
+	for i, lesson := range selected {
+		fmt.Printf("Lesson %d: %s\n", start+i, lesson.Title)
+		fmt.Println(lesson.Explanation)
+		fmt.Println("\nProgram:")
+		fmt.Print(indent(lesson.Source))
+
+		actual := runLesson(lesson)
+		fmt.Println("\nExpected output:")
+		fmt.Print(indent(lesson.Expected))
+		fmt.Println("Actual output:")
+		fmt.Print(indent(actual))
+
+		if actual == lesson.Expected {
+			fmt.Println("Result: PASS")
+		} else {
+			fmt.Println("Result: FAIL")
+			failed++
+		}
+		fmt.Println()
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d lessons failed\n", failed, len(selected))
+		return 1
+	}
+	return 0
+}
+
+// runLesson evaluates a lesson's program and returns its output, or the error it panicked with
+func runLesson(lesson Lesson) (output string) {
+	var buf bytes.Buffer
+	defer func() {
+		if r := recover(); r != nil {
+			output = buf.String() + fmt.Sprintf("error: %v\n", r)
+		}
+	}()
+
+	Eval(&buf, Parse(Lex(lesson.Source)))
+	return buf.String()
+}
+
+// indent prefixes every line of text with two spaces
+func indent(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "  " + line
+		}
+	}
+	return strings.Join(lines, "")
+}